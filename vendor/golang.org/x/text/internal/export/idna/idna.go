@@ -0,0 +1,703 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package idna implements IDNA2008 using the compatibility processing
+// defined by UTS (Unicode Technical Standard) #46, which defines a standard
+// to deal with IDNA2008 and IDNA2003 side by side.
+//
+// IDNA2008 and UTS #46 define internationalized domain names as a set of
+// labels separated by dots. Internally, multi-label names are processed
+// label by label, using "." as the separator on input and output. The
+// public entry points, however, operate on the string as a whole so that
+// callers do not need to split and rejoin names themselves.
+package idna
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// acePrefix is the ASCII Compatible Encoding prefix, as defined in RFC 3490.
+const acePrefix = "xn--"
+
+// A Profile combines a set of validation and mapping rules, dictated mostly
+// by the various RFCs and web browser behavior, into one type. Most users
+// will only need the predefined profiles (Resolve, Display, Lookup and
+// Punycode) rather than constructing their own with New.
+type Profile struct {
+	options
+}
+
+// NOTE: the following code was adapted from GoDaddy's package idna.
+
+// Option configures a Profile at construction time. Options are applied in
+// the order given to New; later options override earlier ones that touch
+// the same field.
+type Option func(*options)
+
+type options struct {
+	transitional    bool
+	useSTD3Rules    bool
+	checkHyphens    bool
+	checkBidi       bool
+	mapForLookup    bool
+	verifyDNSLength bool
+	punycodeOnly    bool
+}
+
+// Transitional sets a Profile to use the Transitional Processing algorithm
+// defined in UTS #46, which maps deviation characters (such as ß and ς)
+// instead of disallowing or passing them through unchanged. Firefox and
+// older browsers still use transitional processing in some code paths.
+func Transitional(transitional bool) Option {
+	return func(o *options) { o.transitional = transitional }
+}
+
+// VerifyDNSLength sets whether a Profile verifies that individual labels
+// and the domain name as a whole stay within the length limits defined by
+// RFC 1035: 63 octets per label and 255 octets for the full name.
+func VerifyDNSLength(verify bool) Option {
+	return func(o *options) { o.verifyDNSLength = verify }
+}
+
+// BidiRule enables the Bidi Rule defined in RFC 5893, which restricts
+// labels containing right-to-left characters to a small set of additional
+// structural constraints so that RTL and LTR labels cannot be combined in
+// ways that are ambiguous to a reader.
+func BidiRule() Option {
+	return func(o *options) { o.checkBidi = true }
+}
+
+// MapForLookup enables the UTS #46 mapping step tailored to lookup
+// (rather than registration) semantics: characters are case-folded and
+// normalized to NFKC, and any character mapLabels categorizes as
+// disallowed is rejected outright instead of being passed through. This
+// is the mapping a resolver should use when looking up a name a user
+// typed or that arrived over the wire.
+//
+// The disallow check is an approximation of the real IdnaMappingTable
+// by Unicode category rather than the generated per-codepoint table; see
+// the mapLabels doc comment for what that means in practice.
+func MapForLookup() Option {
+	return func(o *options) { o.mapForLookup = true }
+}
+
+// StrictDomainName toggles the STD3 ASCII rules from RFC 1122: when
+// enabled, a label may only contain letters, digits, and hyphens after
+// mapping. When disabled, other ASCII characters survive mapping
+// unchanged, which allows names such as DKIM selectors, SRV records, and
+// internal hostnames that rely on underscores to round-trip through the
+// Profile.
+func StrictDomainName(use bool) Option {
+	return func(o *options) { o.useSTD3Rules = use }
+}
+
+// CheckHyphens toggles the hyphen placement rules from RFC 5891 3.1.3:
+// a label must not start or end with a hyphen, and must not have hyphens
+// in the third and fourth position unless it is an ACE label (one that
+// starts with the ACE prefix "xn--").
+func CheckHyphens(check bool) Option {
+	return func(o *options) { o.checkHyphens = check }
+}
+
+// New creates a new Profile according to the given options. The zero value
+// of Profile, as returned by &Profile{}, behaves like New() with no
+// options: it performs no mapping and only the hyphen and length checks
+// that are explicitly enabled.
+func New(o ...Option) *Profile {
+	p := &Profile{}
+	for _, f := range o {
+		f(&p.options)
+	}
+	return p
+}
+
+var (
+	// Resolve is the recommended profile for resolving domain names that
+	// may not be ASCII-only. It is used by Go's net package, for instance,
+	// when dialing a host name. It uses Transitional Processing so that
+	// labels written for IDNA2003-era software still resolve correctly.
+	Resolve = New(MapForLookup(), Transitional(true), BidiRule())
+
+	// Display is the recommended profile for displaying domain names to
+	// a user, for example in a browser's address bar. It uses
+	// Non-Transitional Processing so that deviation characters such as
+	// ß are shown as typed rather than silently mapped.
+	Display = New(MapForLookup(), BidiRule())
+
+	// Lookup is the recommended profile for looking up a name for
+	// inclusion in, or comparison against, a DNS message. It combines
+	// DNS length verification, the full UTS #46 lookup mapping, and the
+	// Bidi Rule, as defined by the IDNA lookup algorithm in RFC 5891.
+	Lookup = New(VerifyDNSLength(true), MapForLookup(), BidiRule())
+
+	// Punycode is a Profile that does raw Punycode (RFC 3492) processing
+	// with no UTS #46 mapping, validation, or ACE-prefix handling. It
+	// exists for callers implementing their own IDNA processing pipeline
+	// (such as DNSSEC tooling or registry validators) that want to reuse
+	// the encoder and decoder on individual labels in isolation.
+	Punycode = &Profile{options{punycodeOnly: true}}
+)
+
+// process implements the algorithm described in section 4 of UTS #46,
+// see https://www.unicode.org/reports/tr46.
+func (p *Profile) process(s string, toASCII bool) (string, error) {
+	var err error
+	if p.mapForLookup {
+		s, err = p.mapLabels(s)
+	}
+	labels := strings.Split(s, ".")
+	for i, label := range labels {
+		if strings.HasPrefix(label, acePrefix) {
+			u, decErr := decode(label[len(acePrefix):])
+			if decErr != nil {
+				if err == nil {
+					err = decErr
+				}
+				continue
+			}
+			labels[i] = u
+			if vErr := p.validateLabel(u, true); vErr != nil && err == nil {
+				err = vErr
+			}
+			continue
+		}
+		if vErr := p.validateLabel(label, false); vErr != nil && err == nil {
+			err = vErr
+		}
+	}
+	s = strings.Join(labels, ".")
+
+	if toASCII {
+		for i, label := range labels {
+			if !ascii(label) {
+				a, encErr := encode(acePrefix, label)
+				if encErr != nil {
+					if err == nil {
+						err = encErr
+					}
+					continue
+				}
+				labels[i] = a
+			}
+		}
+		s = strings.Join(labels, ".")
+	}
+
+	if p.verifyDNSLength && err == nil {
+		err = p.verifyDNSLengthErr(strings.Join(labels, "."))
+	}
+	return s, err
+}
+
+// mapLabels is an approximation of the mapping step of UTS #46 tailored for
+// lookup: it case-folds, maps the deviation characters (ZWNJ, ZWJ, ß, ς)
+// according to p.transitional, rejects characters that disallowed()
+// reports, and finishes with NFKC so that compatibility forms (such as
+// Arabic presentation forms) map to their plain equivalents and a
+// decomposed form and its precomposed equivalent end up identical.
+//
+// This is not a full IdnaMappingTable: that table is generated from
+// Unicode's per-codepoint mapping/disallow data, which this package does
+// not vendor, so disallowed() only approximates it with category-based
+// rules. The set of inputs rejected here differs from what a conformant
+// implementation rejects in both directions: every symbol is rejected,
+// including ones such as U+2603 that ToUnicode produces from an ACE label
+// without error.
+func (p *Profile) mapLabels(s string) (string, error) {
+	var b strings.Builder
+	var err error
+	for _, r := range s {
+		switch {
+		case r == zwnj || r == zwj:
+			if p.transitional {
+				continue // deviation characters are mapped to nothing
+			}
+			b.WriteRune(r)
+		case r == 'ß': // ß
+			if p.transitional {
+				b.WriteString("ss")
+				continue
+			}
+			b.WriteRune(r)
+		case r == 'ς': // final sigma
+			if p.transitional {
+				b.WriteRune('σ')
+				continue
+			}
+			b.WriteRune(r)
+		case disallowed(r):
+			if err == nil {
+				err = labelError{s, "P1"}
+			}
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return norm.NFKC.String(b.String()), err
+}
+
+// zwnj and zwj are the two deviation characters that UTS #46 maps to
+// nothing under Transitional Processing and leaves alone otherwise.
+const (
+	zwnj = '‌'
+	zwj  = '‍'
+
+	// joiners holds zwnj and zwj as a string constant so the Context J
+	// check in validateLabel can call strings.ContainsAny without
+	// allocating a []rune-to-string conversion on every label.
+	joiners = "‌‍"
+)
+
+// disallowed reports whether r is outside the set of characters the
+// mapping table allows through to a label, approximating the
+// IdnaMappingTable's "disallowed"/"disallowed_STD3_valid" entries by
+// category rather than by the generated per-codepoint table: control,
+// format (other than the deviation joiners handled by the caller),
+// private-use, surrogate and unassigned code points, plus symbols, are
+// rejected; ASCII is left to the STD3 rules and letters, digits and marks
+// of any script are let through for mapping/normalization to handle.
+func disallowed(r rune) bool {
+	if r < unicode.MaxASCII {
+		return false // ASCII is governed by StrictDomainName, not mapping.
+	}
+	switch {
+	case !unicode.IsGraphic(r):
+		return true
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.Cs):
+		return true
+	case unicode.IsSymbol(r):
+		return true
+	}
+	return false
+}
+
+// validateLabel checks the RFC 5891 validity criteria that apply
+// regardless of mapping: hyphen placement, STD3 ASCII rules, NFC
+// normalization, the Bidi Rule, and Context J. An empty label passes
+// every check. fromACE reports whether label was just decoded from a Punycode ACE
+// label, in which case the hyphen-in-position-3-and-4 rule is trivially
+// satisfied by the "xn--" prefix itself, but the label still needs its
+// own NFC check: unlike plain-text input, a decoded ACE label never
+// passed through mapLabels's norm.NFKC.String call, so a label that was
+// Punycode-encoded from already non-normalized text would otherwise
+// reach here unnormalized. The Bidi Rule and Context J checks apply to
+// the Unicode form of a label regardless of fromACE: they are a
+// property of the decoded text, not of how it arrived (RFC 5891
+// section 4, RFC 5893).
+func (p *Profile) validateLabel(label string, fromACE bool) error {
+	if label == "" {
+		return nil
+	}
+	if fromACE && !norm.NFC.IsNormalString(label) {
+		return labelError{label, "V1"}
+	}
+	if p.checkHyphens {
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return labelError{label, "V2"}
+		}
+		if !fromACE && len(label) >= 4 && label[2] == '-' && label[3] == '-' {
+			return labelError{label, "V2"}
+		}
+	}
+	if p.useSTD3Rules {
+		for _, r := range label {
+			if r >= unicode.MaxASCII {
+				continue
+			}
+			if !std3Allowed(r) {
+				return labelError{label, "P1"}
+			}
+		}
+	}
+	if p.checkBidi {
+		if err := checkBidiRule(label); err != nil {
+			return err
+		}
+	}
+	if p.mapForLookup && strings.ContainsAny(label, joiners) {
+		// A transitional profile already mapped ZWNJ/ZWJ away in
+		// mapLabels, so by the time a plain-text label reaches here there
+		// is nothing left to find; a non-transitional profile, or any
+		// profile decoding an ACE label that was Punycode-encoded with
+		// the joiners still in it, needs the explicit check.
+		return labelError{label, "C"}
+	}
+	return nil
+}
+
+// checkBidiRule implements an approximation of the Bidi Rule (RFC 5893
+// section 2): a label containing any right-to-left character must not
+// also contain a left-to-right letter, and must start with a
+// right-to-left character (so a leading digit is rejected too). This covers mixed-direction labels such as
+// "gr<arabic>.de" without implementing the full per-character Bidi_Class
+// table RFC 5893 is defined against.
+func checkBidiRule(label string) error {
+	var hasRTL, hasLTRLetter, first bool
+	isFirst := true
+	for _, r := range label {
+		rtl := isRTL(r)
+		if rtl {
+			hasRTL = true
+		} else if unicode.IsLetter(r) {
+			hasLTRLetter = true
+		}
+		if isFirst {
+			first = rtl
+			isFirst = false
+		}
+	}
+	if !hasRTL {
+		return nil // An LTR label has nothing for the Bidi Rule to check.
+	}
+	if hasLTRLetter || !first {
+		return labelError{label, "B"}
+	}
+	return nil
+}
+
+// isRTL reports whether r falls in a block whose characters are
+// predominantly right-to-left (Hebrew, Arabic, Syriac, Thaana, N'Ko, and
+// the Hebrew/Arabic presentation-form blocks), as a stand-in for a full
+// Bidi_Class lookup.
+func isRTL(r rune) bool {
+	switch {
+	case r >= 0x0590 && r <= 0x08FF:
+		return true
+	case r >= 0xFB1D && r <= 0xFDFF:
+		return true
+	case r >= 0xFE70 && r <= 0xFEFF:
+		return true
+	}
+	return false
+}
+
+func std3Allowed(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return true
+	case r == '-':
+		return true
+	}
+	return false
+}
+
+func (p *Profile) verifyDNSLengthErr(s string) error {
+	if s == "" {
+		return labelError{s, "A4"}
+	}
+	for _, label := range strings.Split(s, ".") {
+		if len(label) > 63 {
+			return labelError{label, "A4"}
+		}
+	}
+	if len(s) > 255 {
+		return labelError{s, "A4"}
+	}
+	return nil
+}
+
+func ascii(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// ToASCII converts a domain or label to its ASCII form using the rules
+// configured in the Resolve Profile. For example, ToASCII("ドメイン.テスト")
+// is "xn--eckwd4c7c.xn--zckzah".
+func ToASCII(s string) (string, error) { return Resolve.ToASCII(s) }
+
+// ToUnicode converts a domain or label to its Unicode form using the rules
+// configured in the Resolve Profile. For example, ToUnicode("xn--n3h.com")
+// is "☃.com".
+func ToUnicode(s string) (string, error) { return Resolve.ToUnicode(s) }
+
+// AppendToASCII appends the ASCII form of src to dst using the rules
+// configured in the Resolve Profile.
+func AppendToASCII(dst, src []byte) ([]byte, error) { return Resolve.AppendToASCII(dst, src) }
+
+// AppendToUnicode appends the Unicode form of src to dst using the rules
+// configured in the Resolve Profile.
+func AppendToUnicode(dst, src []byte) ([]byte, error) { return Resolve.AppendToUnicode(dst, src) }
+
+// ToASCII converts a domain or label to its ASCII form. For example,
+// ToASCII("ドメイン.テスト"), where p is a non-transitional profile, returns
+// "xn--eckwd4c7c.xn--zckzah". The Punycode Profile instead Punycode-encodes
+// each dot-separated label verbatim, without the "xn--" prefix or any UTS
+// #46 mapping and validation.
+//
+// ToASCII is a thin wrapper around AppendToASCII; callers on a hot path,
+// such as a DNS resolver or HTTP client processing many hostnames, should
+// call AppendToASCII directly to reuse a buffer across calls.
+//
+// For a string that is already ASCII, needs no case-folding, and
+// contains no "xn--" label to decode and revalidate, the string form of
+// the fast path returns s itself: slicing or returning a string never
+// copies, whereas routing through AppendToASCII would have to convert s
+// to a []byte first. Anything that needs mapping, or contains a
+// Punycode label, goes through AppendToASCII as normal so that label
+// still gets decoded and run through the Bidi/Context J/NFC checks.
+func (p *Profile) ToASCII(s string) (string, error) {
+	if !p.punycodeOnly && p.asciiFastPath(s) && !strings.Contains(s, acePrefix) {
+		if err := p.validateASCIILabelsString(s); err != nil {
+			return s, err
+		}
+		return s, nil
+	}
+	b, err := p.AppendToASCII(make([]byte, 0, len(s)), []byte(s))
+	return string(b), err
+}
+
+// ToUnicode converts a domain or label to its Unicode form. For example,
+// ToUnicode("xn--n3h.com") returns "☃.com". The Punycode Profile instead
+// Punycode-decodes each dot-separated label verbatim, treating it as a raw
+// Punycode string rather than an ACE label prefixed with "xn--".
+//
+// ToUnicode is a thin wrapper around AppendToUnicode; see AppendToUnicode
+// for a version that avoids the per-call allocations of the string API.
+// It shares ToASCII's no-copy fast path for ASCII input that contains no
+// "xn--" label to decode.
+func (p *Profile) ToUnicode(s string) (string, error) {
+	if !p.punycodeOnly && p.asciiFastPath(s) && !strings.Contains(s, acePrefix) {
+		if err := p.validateASCIILabelsString(s); err != nil {
+			return s, err
+		}
+		return s, nil
+	}
+	b, err := p.AppendToUnicode(make([]byte, 0, len(s)), []byte(s))
+	return string(b), err
+}
+
+// aceBytes is acePrefix as a []byte, allocated once rather than on every
+// AppendToUnicode call.
+var aceBytes = []byte(acePrefix)
+
+// AppendToASCII appends the ASCII form of src to dst and returns the
+// extended buffer, applying the same rules as ToASCII. Labels that are
+// already ASCII, contain no "xn--" label to decode and revalidate, and
+// satisfy p's validation rules are appended to dst directly:
+// validateASCIILabelsBytes walks src in place and never splits it into a
+// slice of labels or converts a label to a string, so this path
+// allocates nothing beyond growing dst itself. Inputs that need mapping,
+// Punycode decoding, or contain a non-ASCII label fall back to process.
+func (p *Profile) AppendToASCII(dst, src []byte) ([]byte, error) {
+	if p.punycodeOnly {
+		out, err := p.rawPunycode(string(src), true)
+		return append(dst, out...), err
+	}
+	if p.asciiFastPathBytes(src) && !bytes.Contains(src, aceBytes) {
+		if err := p.validateASCIILabelsBytes(src); err != nil {
+			return append(dst, src...), err
+		}
+		return append(dst, src...), nil
+	}
+	out, err := p.process(string(src), true)
+	return append(dst, out...), err
+}
+
+// AppendToUnicode appends the Unicode form of src to dst and returns the
+// extended buffer, applying the same rules as ToUnicode. As with
+// AppendToASCII, ASCII input that contains no "xn--" labels and already
+// satisfies p's validation rules is appended to dst unchanged.
+func (p *Profile) AppendToUnicode(dst, src []byte) ([]byte, error) {
+	if p.punycodeOnly {
+		out, err := p.rawPunycode(string(src), false)
+		return append(dst, out...), err
+	}
+	if p.asciiFastPathBytes(src) && !bytes.Contains(src, aceBytes) {
+		if err := p.validateASCIILabelsBytes(src); err != nil {
+			return append(dst, src...), err
+		}
+		return append(dst, src...), nil
+	}
+	out, err := p.process(string(src), false)
+	return append(dst, out...), err
+}
+
+// asciiFastPathBytes is asciiFastPath for a []byte buffer: it reports
+// whether b is plain ASCII that p would pass through unchanged; for a
+// mapForLookup profile that also means it contains no uppercase letters
+// that still need case-folding. AppendToASCII/AppendToUnicode must check this, not
+// just asciiBytes, or a profile like Resolve would copy "WWW.EXAMPLE.COM"
+// straight through instead of folding it to lowercase.
+func (p *Profile) asciiFastPathBytes(b []byte) bool {
+	hasUpper := false
+	for _, c := range b {
+		if c >= unicode.MaxASCII {
+			return false
+		}
+		if c >= 'A' && c <= 'Z' {
+			hasUpper = true
+		}
+	}
+	return !hasUpper || !p.mapForLookup
+}
+
+// asciiFastPath reports whether s is plain ASCII that p would leave
+// unchanged, so the string API can skip mapping and normalization
+// entirely and return s itself. A mapForLookup profile must still
+// case-fold uppercase ASCII, so it cannot take this shortcut for those
+// inputs; everything else about mapping (deviation characters, the
+// disallow check) only ever touches non-ASCII runes.
+func (p *Profile) asciiFastPath(s string) bool {
+	hasUpper := false
+	for i := 0; i < len(s); i++ {
+		if s[i] >= unicode.MaxASCII {
+			return false
+		}
+		if s[i] >= 'A' && s[i] <= 'Z' {
+			hasUpper = true
+		}
+	}
+	return !hasUpper || !p.mapForLookup
+}
+
+// validateASCIILabelsString runs validateLabel over each dot-separated
+// label of s. Every label is a slice of s, so, unlike converting a []byte
+// label to a string, this allocates nothing: a Go string slice shares its
+// parent's backing array.
+func (p *Profile) validateASCIILabelsString(s string) error {
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == '.' {
+			if err := p.validateLabel(s[start:i], false); err != nil {
+				return err
+			}
+			start = i + 1
+		}
+	}
+	if p.verifyDNSLength {
+		return p.verifyDNSLengthErr(s)
+	}
+	return nil
+}
+
+// validateASCIILabelsBytes runs the hyphen, STD3, and DNS-length checks
+// over an already-ASCII buffer directly, byte by byte, so the
+// AppendToASCII/AppendToUnicode fast path never allocates a []byte slice
+// header per label (as bytes.Split would) or a string per label (as
+// converting each label for validateLabel would).
+func (p *Profile) validateASCIILabelsBytes(b []byte) error {
+	start := 0
+	for i := 0; i <= len(b); i++ {
+		if i == len(b) || b[i] == '.' {
+			if err := p.validateLabelBytes(b[start:i]); err != nil {
+				return err
+			}
+			start = i + 1
+		}
+	}
+	if p.verifyDNSLength {
+		if len(b) == 0 {
+			return labelError{"", "A4"}
+		}
+		if len(b) > 255 {
+			return labelError{string(b), "A4"}
+		}
+	}
+	return nil
+}
+
+// validateLabelBytes is the byte-slice counterpart of validateLabel's
+// hyphen and STD3 checks. It only needs to handle the ASCII fast path, so
+// it skips the Bidi Rule and Context J checks, which only ever apply to
+// non-ASCII text.
+func (p *Profile) validateLabelBytes(label []byte) error {
+	if len(label) == 0 {
+		return nil
+	}
+	if p.checkHyphens {
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return labelError{string(label), "V2"}
+		}
+		if len(label) >= 4 && label[2] == '-' && label[3] == '-' {
+			return labelError{string(label), "V2"}
+		}
+	}
+	if p.useSTD3Rules {
+		for _, c := range label {
+			if !std3Allowed(rune(c)) {
+				return labelError{string(label), "P1"}
+			}
+		}
+	}
+	if p.verifyDNSLength && len(label) > 63 {
+		return labelError{string(label), "A4"}
+	}
+	return nil
+}
+
+// rawPunycode implements the Punycode Profile: it runs the RFC 3492
+// encoder or decoder over each dot-separated label with no mapping,
+// validation, or ACE-prefix handling.
+func (p *Profile) rawPunycode(s string, toASCII bool) (string, error) {
+	labels := strings.Split(s, ".")
+	var err error
+	for i, label := range labels {
+		var out string
+		var e error
+		if toASCII {
+			out, e = encode("", label)
+		} else {
+			out, e = decode(label)
+		}
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			continue
+		}
+		labels[i] = out
+	}
+	return strings.Join(labels, "."), err
+}
+
+func (p *Profile) String() string {
+	s := ""
+	if p.transitional {
+		s = "Transitional"
+	} else {
+		s = "NonTransitional"
+	}
+	if p.useSTD3Rules {
+		s += ":UseSTD3Rules"
+	}
+	if p.checkHyphens {
+		s += ":CheckHyphens"
+	}
+	if p.checkBidi {
+		s += ":CheckBidi"
+	}
+	if p.mapForLookup {
+		s += ":MapForLookup"
+	}
+	if p.verifyDNSLength {
+		s += ":VerifyDNSLength"
+	}
+	return s
+}
+
+// labelError indicates an error with a particular label in a domain name,
+// tagged with one of the single-letter error categories used by the
+// Unicode IdnaTest.txt conformance suite (P: processing, V: validity,
+// A: to ASCII, B: bidi, C: context J).
+type labelError struct{ label, code_ string }
+
+func (e labelError) code() string { return e.code_ }
+func (e labelError) Error() string {
+	return fmt.Sprintf("idna: invalid label %q", e.label)
+}
+
+// punyError reports a failure in the Punycode encoder or decoder, which
+// always corresponds to the "to ASCII" category (A4: bad input) in the
+// Unicode conformance suite.
+func punyError(s string) error { return labelError{s, "A4"} }
@@ -33,6 +33,40 @@ func TestAllocToASCII(t *testing.T) {
 	}
 }
 
+func TestAllocAppendToASCII(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	avg := testtext.AllocsPerRun(1000, func() {
+		buf, _ = AppendToASCII(buf[:0], []byte("www.golang.org"))
+	})
+	if avg > 0 {
+		t.Errorf("got %f; want 0", avg)
+	}
+}
+
+func TestAppendRoundTrip(t *testing.T) {
+	nonNormalized, _ := encode(acePrefix, "ạ̢")
+	testCases := []string{
+		"www.golang.org",
+		"ドメイン.テスト",
+		"xn--n3h.com",
+		// A non-normalized ACE label: unlike "xn--n3h.com", whose ASCII
+		// form is byte-identical whether or not it is actually decoded
+		// and revalidated, this one must be rejected, so it catches a
+		// fast path that skips decoding an already-ASCII "xn--" label.
+		nonNormalized + ".com",
+	}
+	for _, tc := range testCases {
+		want, wantErr := Resolve.ToASCII(tc)
+		got, gotErr := Resolve.AppendToASCII(nil, []byte(tc))
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("AppendToASCII(%q) error = %v; ToASCII(%q) error = %v", tc, gotErr, tc, wantErr)
+		}
+		if string(got) != want {
+			t.Errorf("AppendToASCII(%q) = %q; want %q", tc, got, want)
+		}
+	}
+}
+
 // doTest performs a single test f(input) and verifies that the output matches
 // out and that the returned error is expected. The errors string contains
 // all allowed error codes as categorized in
@@ -90,8 +124,13 @@ func TestLabelErrors(t *testing.T) {
 		name string
 		f    func(string) (string, error)
 	}
-	resolve := kind{"ToASCII", Resolve.ToASCII}
-	display := kind{"ToUnicode", Display.ToUnicode}
+	resolve := kind{"resolve", Resolve.ToASCII}
+	display := kind{"display", Display.ToUnicode}
+	std3 := kind{"std3", New(StrictDomainName(true), VerifyDNSLength(true)).ToASCII}
+	noStd3 := kind{"noStd3", New(StrictDomainName(false)).ToASCII}
+	hyphens := kind{"hyphens", New(CheckHyphens(true)).ToASCII}
+	noHyphens := kind{"noHyphens", New(CheckHyphens(false)).ToASCII}
+	punyA := kind{"punyA", Punycode.ToASCII}
 	testCases := []struct {
 		kind
 		input   string
@@ -145,6 +184,24 @@ func TestLabelErrors(t *testing.T) {
 		// to look up the input punycode.
 		{resolve, encode("a\u0323\u0322") + ".com", "xn--a-tdbc.com", "V1"},
 		{display, encode("a\u0323\u0322") + ".com", "a\u0323\u0322.com", "V1"},
+
+		// StrictDomainName(true) rejects underscores and other non-STD3
+		// ASCII; StrictDomainName(false) lets DKIM selectors and similar
+		// internal hostnames round-trip.
+		{std3, "_dmarc.example.com", "_dmarc.example.com", "P1"},
+		{noStd3, "_dmarc.example.com", "_dmarc.example.com", ""},
+
+		// CheckHyphens(true) rejects leading/trailing hyphens and a
+		// "--" in positions 3 and 4 unless the label is an ACE label.
+		{hyphens, "-abc.com", "-abc.com", "V2"},
+		{hyphens, "abc-.com", "abc-.com", "V2"},
+		{hyphens, "ab--cd.com", "ab--cd.com", "V2"},
+		{noHyphens, "-abc.com", "-abc.com", ""},
+
+		// Punycode is a raw RFC 3492 codec: it ignores STD3, hyphen, and
+		// Bidi rules and never adds or strips the "xn--" ACE prefix.
+		{punyA, "example", "example-", ""},
+		{punyA, "_dmarc", "_dmarc-", ""},
 	}
 
 	for _, tc := range testCases {
@@ -152,6 +209,14 @@ func TestLabelErrors(t *testing.T) {
 	}
 }
 
+// TestConformance runs the tests in IdnaTest.txt. Each row has four
+// columns (type, source, toUnicode, toASCII), and a file may add a fifth
+// "IDNA2008 Status" column holding NV8 or XV8. Rather than pre-detecting
+// which layout the whole file uses from one row (most rows leave that
+// column blank, so that guess is unreliable), the loop just reads column
+// 4 on every row: a file without that column reads as "" there, a row
+// with no status is equally "", and either way there's simply nothing to
+// do for that row.
 func TestConformance(t *testing.T) {
 	testtext.SkipIfNotLong(t)
 
@@ -167,19 +232,38 @@ func TestConformance(t *testing.T) {
 	}))
 	transitional := New(Transitional(true), VerifyDNSLength(true))
 	nonTransitional := New(VerifyDNSLength(true))
+	// idna2008 applies the STD3, hyphen and Bidi rules with no UTS #46
+	// mapping. It is used to check the NV8/XV8 status column. It has no
+	// per-code-point IDNA2008 validity check and, without mapping, no
+	// Context J check, so it accepts non-ASCII characters such as U+2603
+	// that strict IDNA2008 does not allow.
+	idna2008 := New(VerifyDNSLength(true), CheckHyphens(true), StrictDomainName(true), BidiRule())
+
+	// bySection overrides the per-row T/N/B column for section headers
+	// that exercise a single kind of processing for every row in the
+	// section rather than enumerating it per row.
+	bySection := map[string][]*Profile{
+		"bidi":            {transitional, nonTransitional},
+		"contextj":        {transitional, nonTransitional},
+		"contexto":        {transitional, nonTransitional},
+		"transitional":    {transitional},
+		"nontransitional": {nonTransitional},
+	}
+
 	for p.Next() {
 		started = true
 
 		// What to test
-		profiles := []*Profile{}
-		switch p.String(0) {
-		case "T":
-			profiles = append(profiles, transitional)
-		case "N":
-			profiles = append(profiles, nonTransitional)
-		case "B":
-			profiles = append(profiles, transitional)
-			profiles = append(profiles, nonTransitional)
+		profiles := bySection[section]
+		if profiles == nil {
+			switch p.String(0) {
+			case "T":
+				profiles = []*Profile{transitional}
+			case "N":
+				profiles = []*Profile{nonTransitional}
+			case "B":
+				profiles = []*Profile{transitional, nonTransitional}
+			}
 		}
 
 		src := unescape(p.String(1))
@@ -203,14 +287,27 @@ func TestConformance(t *testing.T) {
 			wantToASCII = ""
 		}
 
-		// TODO: also do IDNA tests.
-		// invalidInIDNA2008 := p.String(4) == "NV8"
-
 		for _, p := range profiles {
 			name := fmt.Sprintf("%s:%s", section, p)
 			doTest(t, p.ToUnicode, name+":ToUnicode", src, wantToUnicode, wantErrToUnicode)
 			doTest(t, p.ToASCII, name+":ToASCII", src, wantToASCII, wantErrToASCII)
 		}
+
+		// The IDNA2008 status column, present from the 10.0.0 schema
+		// onward, marks inputs that UTS #46 accepts but strict IDNA2008
+		// must reject (NV8) or only accepts under IDNA2008 (XV8). Run
+		// those through idna2008 so Bidi Rule regressions there
+		// don't silently pass just because the T/N/B columns are clean.
+		// On a 9.0.0 file, or a 10.0.0+ row with no status, p.String(4)
+		// is "" and neither case fires.
+		switch status := p.String(4); status {
+		case "NV8":
+			name := fmt.Sprintf("%s:idna2008:%s", section, status)
+			doTest(t, idna2008.ToASCII, name, src, "", "P1 V2 B C")
+		case "XV8":
+			name := fmt.Sprintf("%s:idna2008:%s", section, status)
+			doTest(t, idna2008.ToASCII, name, src, "", "")
+		}
 	}
 }
 